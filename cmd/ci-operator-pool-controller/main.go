@@ -0,0 +1,323 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	coreapi "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	coreclientset "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+
+	projectapi "github.com/openshift/api/project/v1"
+	buildclientset "github.com/openshift/client-go/build/clientset/versioned/typed/build/v1"
+	"github.com/openshift/client-go/project/clientset/versioned"
+)
+
+// ci-operator-pool-controller keeps a namespace-pool topped up to its desired size, reaps leases
+// that have outlived their TTL, and enforces the same idle timer that used to live in the inline
+// bash cleanup script run inside each namespace.
+func bindOptions() *options {
+	opt := &options{}
+	flag.StringVar(&opt.kubeconfig, "kubeconfig", "", "Path to a kubeconfig file to use instead of in-cluster configuration.")
+	flag.StringVar(&opt.pool, "pool", "", "Name of the namespace pool to maintain.")
+	flag.IntVar(&opt.size, "size", 5, "Desired number of free namespaces to keep in the pool.")
+	flag.DurationVar(&opt.leaseTTL, "lease-ttl", 4*time.Hour, "Maximum amount of time a namespace may stay leased before its lease is reaped, regardless of pod activity.")
+	flag.DurationVar(&opt.idleTimeout, "idle-timeout", 20*time.Minute, "Reap a leased namespace's lease if no run-once pod has been observed running in it for this long, mirroring the old inline cleanup script's idle check.")
+	flag.DurationVar(&opt.resyncInterval, "resync-interval", time.Minute, "How often to check the pool size, lease ages and pod activity.")
+	return opt
+}
+
+type options struct {
+	kubeconfig     string
+	pool           string
+	size           int
+	leaseTTL       time.Duration
+	idleTimeout    time.Duration
+	resyncInterval time.Duration
+
+	clusterConfig *rest.Config
+}
+
+func (o *options) Validate() error {
+	if o.pool == "" {
+		return fmt.Errorf("pool name must be provided with `--pool`")
+	}
+	if o.size <= 0 {
+		return fmt.Errorf("pool size must be positive")
+	}
+	return nil
+}
+
+func (o *options) Complete() error {
+	clusterConfig, err := loadClusterConfig(o.kubeconfig)
+	if err != nil {
+		return fmt.Errorf("failed to load cluster config: %v", err)
+	}
+	o.clusterConfig = clusterConfig
+	return nil
+}
+
+func loadClusterConfig(kubeconfig string) (*rest.Config, error) {
+	if kubeconfig == "" {
+		if clusterConfig, err := rest.InClusterConfig(); err == nil {
+			return clusterConfig, nil
+		}
+	}
+
+	loadingRules := clientcmd.NewDefaultClientConfigLoadingRules()
+	if kubeconfig != "" {
+		loadingRules.ExplicitPath = kubeconfig
+	}
+	credentials, err := loadingRules.Load()
+	if err != nil {
+		return nil, fmt.Errorf("could not load credentials from config: %v", err)
+	}
+	clusterConfig, err := clientcmd.NewDefaultClientConfig(*credentials, &clientcmd.ConfigOverrides{}).ClientConfig()
+	if err != nil {
+		return nil, fmt.Errorf("could not load client configuration: %v", err)
+	}
+	return clusterConfig, nil
+}
+
+const (
+	poolLabel          = "ci.openshift.io/pool"
+	leasedByAnnotation = "ci.openshift.io/leased-by"
+	leasedAtAnnotation = "ci.openshift.io/leased-at"
+	// idleSinceAnnotation records the first reconcile pass at which a leased namespace was
+	// observed with no run-once pod running in it, mirroring the idle check the inline bash
+	// cleanup script used to perform from inside the namespace itself.
+	idleSinceAnnotation = "ci.openshift.io/idle-since"
+)
+
+func (o *options) Run() error {
+	coreClient, err := coreclientset.NewForConfig(o.clusterConfig)
+	if err != nil {
+		return fmt.Errorf("could not get core client for cluster config: %v", err)
+	}
+	projectClient, err := versioned.NewForConfig(o.clusterConfig)
+	if err != nil {
+		return fmt.Errorf("could not get project client for cluster config: %v", err)
+	}
+	buildClient, err := buildclientset.NewForConfig(o.clusterConfig)
+	if err != nil {
+		return fmt.Errorf("could not get build client for cluster config: %v", err)
+	}
+
+	return wait.PollImmediateInfinite(o.resyncInterval, func() (bool, error) {
+		if err := o.reconcile(coreClient, projectClient, buildClient); err != nil {
+			log.Printf("error: Failed to reconcile pool %q: %v", o.pool, err)
+		}
+		return false, nil
+	})
+}
+
+// reconcile reaps leases that have either outlived leaseTTL outright or sat idle (no run-once pod
+// running) for longer than idleTimeout, and tops the pool back up to the desired size by
+// requesting fresh projects labelled for this pool.
+func (o *options) reconcile(coreClient coreclientset.CoreV1Interface, projectClient versioned.Interface, buildClient buildclientset.BuildV1Interface) error {
+	namespaces, err := coreClient.Namespaces().List(meta.ListOptions{
+		LabelSelector: fmt.Sprintf("%s=%s", poolLabel, o.pool),
+	})
+	if err != nil {
+		return fmt.Errorf("could not list pool namespaces: %v", err)
+	}
+
+	free := 0
+	for _, ns := range namespaces.Items {
+		leasedAt, isLeased := ns.Annotations[leasedAtAnnotation]
+		if !isLeased {
+			free++
+			continue
+		}
+
+		reapReason, err := o.leaseShouldBeReaped(coreClient, ns, leasedAt)
+		if err != nil {
+			log.Printf("error: %v", err)
+			continue
+		}
+		if reapReason == "" {
+			continue
+		}
+
+		log.Printf("Reaping lease on namespace %s: %s", ns.Name, reapReason)
+		if err := resetLeasedNamespace(coreClient, buildClient, ns.Name); err != nil {
+			log.Printf("error: Failed to clear namespace %s before returning it to the pool: %v", ns.Name, err)
+			continue
+		}
+		if err := releaseLease(coreClient, ns.Name); err != nil {
+			log.Printf("error: Failed to release lease on namespace %s: %v", ns.Name, err)
+			continue
+		}
+		free++
+	}
+
+	missing := o.size - free
+	if missing <= 0 {
+		return nil
+	}
+	log.Printf("Pool %q has %d free namespaces, requesting %d more", o.pool, free, missing)
+	for i := 0; i < missing; i++ {
+		name := fmt.Sprintf("ci-op-pool-%s-%d", o.pool, time.Now().UnixNano())
+		if _, err := projectClient.ProjectV1().ProjectRequests().Create(&projectapi.ProjectRequest{
+			ObjectMeta: meta.ObjectMeta{
+				Name:   name,
+				Labels: map[string]string{poolLabel: o.pool},
+			},
+		}); err != nil && !errors.IsAlreadyExists(err) {
+			return fmt.Errorf("could not create pool namespace: %v", err)
+		}
+		if err := ensurePoolLabel(coreClient, name, o.pool); err != nil {
+			return fmt.Errorf("could not confirm pool label on namespace %s: %v", name, err)
+		}
+	}
+	return nil
+}
+
+// ensurePoolLabel confirms poolLabel actually landed on the Namespace a ProjectRequest produced.
+// The project-request flow is driven by a cluster-configured project template and is not
+// guaranteed to propagate ObjectMeta.Labels from the ProjectRequest onto the resulting Namespace,
+// so reconcile's own List(LabelSelector: poolLabel=...) would otherwise never find these
+// namespaces again, causing it to request the same "missing" namespaces on every resync forever.
+func ensurePoolLabel(coreClient coreclientset.CoreV1Interface, name, pool string) error {
+	ns, err := coreClient.Namespaces().Get(name, meta.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("could not retrieve namespace: %v", err)
+	}
+	if ns.Labels[poolLabel] == pool {
+		return nil
+	}
+	log.Printf("Namespace %s did not inherit %s from its ProjectRequest, patching it directly", name, poolLabel)
+	updated := ns.DeepCopy()
+	if updated.Labels == nil {
+		updated.Labels = map[string]string{}
+	}
+	updated.Labels[poolLabel] = pool
+	if _, err := coreClient.Namespaces().Update(updated); err != nil {
+		return fmt.Errorf("could not patch namespace label: %v", err)
+	}
+	return nil
+}
+
+// leaseShouldBeReaped decides whether a leased namespace's lease should be reclaimed: either the
+// hard leaseTTL backstop has elapsed, or no run-once pod has been seen running in the namespace
+// for longer than idleTimeout. It also maintains the idleSinceAnnotation bookkeeping that the idle
+// check depends on. It returns a human-readable reason, or "" if the lease should be left alone.
+func (o *options) leaseShouldBeReaped(coreClient coreclientset.CoreV1Interface, ns coreapi.Namespace, leasedAt string) (string, error) {
+	leasedSince, err := time.Parse(time.RFC3339, leasedAt)
+	if err != nil {
+		return "", fmt.Errorf("namespace %s has an unparseable %s annotation: %v", ns.Name, leasedAtAnnotation, err)
+	}
+	if time.Since(leasedSince) > o.leaseTTL {
+		return fmt.Sprintf("held since %s, past the %s lease TTL", leasedAt, o.leaseTTL), nil
+	}
+
+	pods, err := coreClient.Pods(ns.Name).List(meta.ListOptions{})
+	if err != nil {
+		return "", fmt.Errorf("could not list pods in namespace %s: %v", ns.Name, err)
+	}
+	if podsStillRunning(pods.Items) {
+		if _, idle := ns.Annotations[idleSinceAnnotation]; idle {
+			updated := ns.DeepCopy()
+			delete(updated.Annotations, idleSinceAnnotation)
+			if _, err := coreClient.Namespaces().Update(updated); err != nil && !errors.IsConflict(err) {
+				return "", fmt.Errorf("could not clear idle marker on namespace %s: %v", ns.Name, err)
+			}
+		}
+		return "", nil
+	}
+
+	idleSince, marked := ns.Annotations[idleSinceAnnotation]
+	if !marked {
+		updated := ns.DeepCopy()
+		if updated.Annotations == nil {
+			updated.Annotations = map[string]string{}
+		}
+		updated.Annotations[idleSinceAnnotation] = time.Now().Format(time.RFC3339)
+		if _, err := coreClient.Namespaces().Update(updated); err != nil && !errors.IsConflict(err) {
+			return "", fmt.Errorf("could not mark namespace %s idle: %v", ns.Name, err)
+		}
+		return "", nil
+	}
+
+	idleStart, err := time.Parse(time.RFC3339, idleSince)
+	if err != nil {
+		return "", fmt.Errorf("namespace %s has an unparseable %s annotation: %v", ns.Name, idleSinceAnnotation, err)
+	}
+	if time.Since(idleStart) > o.idleTimeout {
+		return fmt.Sprintf("idle (no running pods) since %s", idleSince), nil
+	}
+	return "", nil
+}
+
+// podsStillRunning reports whether any run-once pod in the namespace is still pending or running,
+// the same condition the old inline bash cleanup script polled for via `oc get pods`.
+func podsStillRunning(pods []coreapi.Pod) bool {
+	for _, pod := range pods {
+		if pod.Spec.RestartPolicy != coreapi.RestartPolicyNever {
+			continue
+		}
+		switch pod.Status.Phase {
+		case coreapi.PodPending, coreapi.PodRunning, coreapi.PodUnknown:
+			return true
+		}
+	}
+	return false
+}
+
+// resetLeasedNamespace clears out everything a run left behind - builds, pods and secrets - while
+// keeping imagestreams, mirroring options.resetPooledNamespace on the ci-operator side. The
+// controller performs the same reset here so a namespace whose ci-operator process was killed
+// before it could clean up after itself (OOM, SIGKILL) isn't handed back to the pool dirty.
+func resetLeasedNamespace(coreClient coreclientset.CoreV1Interface, buildClient buildclientset.BuildV1Interface, namespace string) error {
+	if err := buildClient.Builds(namespace).DeleteCollection(&meta.DeleteOptions{}, meta.ListOptions{}); err != nil && !errors.IsNotFound(err) {
+		return fmt.Errorf("could not clear builds: %v", err)
+	}
+	if err := coreClient.Pods(namespace).DeleteCollection(&meta.DeleteOptions{}, meta.ListOptions{}); err != nil && !errors.IsNotFound(err) {
+		return fmt.Errorf("could not clear pods: %v", err)
+	}
+	if err := coreClient.Secrets(namespace).DeleteCollection(&meta.DeleteOptions{}, meta.ListOptions{}); err != nil && !errors.IsNotFound(err) {
+		return fmt.Errorf("could not clear secrets: %v", err)
+	}
+	return nil
+}
+
+func releaseLease(coreClient coreclientset.CoreV1Interface, namespace string) error {
+	ns, err := coreClient.Namespaces().Get(namespace, meta.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("could not retrieve namespace: %v", err)
+	}
+	delete(ns.Annotations, leasedByAnnotation)
+	delete(ns.Annotations, leasedAtAnnotation)
+	delete(ns.Annotations, idleSinceAnnotation)
+	if _, err := coreClient.Namespaces().Update(ns); err != nil {
+		return fmt.Errorf("could not update namespace: %v", err)
+	}
+	return nil
+}
+
+func main() {
+	opt := bindOptions()
+	flag.Parse()
+
+	if err := opt.Validate(); err != nil {
+		fmt.Printf("Invalid options: %v\n", err)
+		os.Exit(1)
+	}
+
+	if err := opt.Complete(); err != nil {
+		fmt.Printf("Invalid environment: %v\n", err)
+		os.Exit(1)
+	}
+
+	if err := opt.Run(); err != nil {
+		fmt.Printf("error: %v\n", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,367 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"strings"
+
+	coreapi "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/openshift/ci-operator/pkg/api"
+	"github.com/openshift/ci-operator/pkg/steps"
+)
+
+// tektonCLIImage is the image every emitted Task runs its `oc`/shell steps in. It needs nothing
+// beyond the `oc` binary and a shell, since every step here only wraps the same OpenShift API
+// calls the in-process steps in pkg/steps would have made directly.
+const tektonCLIImage = "quay.io/openshift/origin-cli:latest"
+
+// pipelineImageRepoParam is the name of the Param the emitted Pipeline and its image-mirror and
+// template Tasks use to learn the
+// pull spec of the namespace's pipeline ImageStream, since Tekton has no native notion of an
+// OpenShift ImageStream - ci-operator resolves it once, up front, and passes it down as a plain
+// string instead.
+const pipelineImageRepoParam = "pipeline-image-repo"
+
+// pipelineWorkspaceName is the shared Workspace every Task that needs to read or write files
+// across step boundaries mounts, standing in for the scratch space steps would otherwise share via
+// the job namespace itself.
+const pipelineWorkspaceName = "pipeline"
+
+// tektonParam doubles as a Tekton v1beta1 ParamSpec (Name+Default, declared on a Task or Pipeline)
+// and a Param (Name+Value, passed from a PipelineTask or PipelineRun) - Tekton's own wire format
+// distinguishes the two only by which of Default/Value is populated.
+type tektonParam struct {
+	Name    string `json:"name"`
+	Default string `json:"default,omitempty"`
+	Value   string `json:"value,omitempty"`
+}
+
+// tektonWorkspaceDeclaration is how a Task or Pipeline declares a named workspace slot it expects
+// its caller to bind.
+type tektonWorkspaceDeclaration struct {
+	Name string `json:"name"`
+}
+
+// tektonWorkspaceBinding is how a PipelineTask binds a Pipeline-level workspace through to the
+// Task it references, or a PipelineRun binds an actual PersistentVolumeClaim to a Pipeline-level
+// workspace.
+type tektonWorkspaceBinding struct {
+	Name                  string                    `json:"name"`
+	Workspace             string                    `json:"workspace,omitempty"`
+	PersistentVolumeClaim *tektonPVCWorkspaceSource `json:"persistentVolumeClaim,omitempty"`
+}
+
+type tektonPVCWorkspaceSource struct {
+	ClaimName string `json:"claimName"`
+}
+
+// tektonContainerStep is one container within a Task's pod, e.g. the `oc tag` invocation that
+// stands in for an image-mirror step.
+type tektonContainerStep struct {
+	Name    string   `json:"name"`
+	Image   string   `json:"image"`
+	Command []string `json:"command,omitempty"`
+	Args    []string `json:"args,omitempty"`
+}
+
+// tektonTaskSpec is the Task body: what it needs (params, workspaces) and what it runs.
+type tektonTaskSpec struct {
+	Params     []tektonParam                `json:"params,omitempty"`
+	Workspaces []tektonWorkspaceDeclaration `json:"workspaces,omitempty"`
+	Steps      []tektonContainerStep        `json:"steps"`
+}
+
+// tektonTaskResource is a standalone Tekton v1beta1 Task, one per step of the build graph.
+type tektonTaskResource struct {
+	APIVersion string          `json:"apiVersion"`
+	Kind       string          `json:"kind"`
+	Metadata   meta.ObjectMeta `json:"metadata"`
+	Spec       tektonTaskSpec  `json:"spec"`
+}
+
+// tektonTask is the subset of a Tekton v1beta1 PipelineTask that ci-operator needs to describe one
+// step of the build graph: its name, what it must run after, the Task it refers to, and the
+// params/workspaces it forwards down to that Task.
+type tektonTask struct {
+	Name       string                   `json:"name"`
+	RunAfter   []string                 `json:"runAfter,omitempty"`
+	Params     []tektonParam            `json:"params,omitempty"`
+	Workspaces []tektonWorkspaceBinding `json:"workspaces,omitempty"`
+	TaskRef    struct {
+		Name string `json:"name"`
+	} `json:"taskRef"`
+}
+
+// tektonPipeline is a minimal Tekton v1beta1 Pipeline carrying one PipelineTask per step.Step.
+type tektonPipeline struct {
+	APIVersion string          `json:"apiVersion"`
+	Kind       string          `json:"kind"`
+	Metadata   meta.ObjectMeta `json:"metadata"`
+	Spec       struct {
+		Params     []tektonParam                `json:"params,omitempty"`
+		Workspaces []tektonWorkspaceDeclaration `json:"workspaces,omitempty"`
+		Tasks      []tektonTask                 `json:"tasks"`
+	} `json:"spec"`
+}
+
+// tektonPipelineRun is the companion PipelineRun that actually triggers execution of a Pipeline.
+type tektonPipelineRun struct {
+	APIVersion string          `json:"apiVersion"`
+	Kind       string          `json:"kind"`
+	Metadata   meta.ObjectMeta `json:"metadata"`
+	Spec       struct {
+		PipelineRef struct {
+			Name string `json:"name"`
+		} `json:"pipelineRef"`
+		Params     []tektonParam            `json:"params,omitempty"`
+		Workspaces []tektonWorkspaceBinding `json:"workspaces,omitempty"`
+	} `json:"spec"`
+}
+
+// emitPipeline walks the build graph and serializes it as a declarative pipeline manifest instead
+// of executing it in-process. With --dry-run the manifest is printed to stdout so it can be
+// inspected or replayed; otherwise it is applied to the cluster and the run's logs are streamed,
+// which lets the same ci-operator configuration be executed on non-OpenShift clusters that only
+// have the target pipeline engine installed.
+func (o *options) emitPipeline(graph []*api.StepNode) error {
+	switch o.emit {
+	case "tekton":
+		return o.emitTekton(graph)
+	case "argo", "kbatch":
+		return fmt.Errorf("--emit=%s is not implemented yet", o.emit)
+	default:
+		return fmt.Errorf("unrecognized --emit mode %q, must be one of: tekton, argo, kbatch", o.emit)
+	}
+}
+
+func (o *options) emitTekton(graph []*api.StepNode) error {
+	pipelineName := fmt.Sprintf("%s-pipeline", o.namespace)
+
+	pipeline := tektonPipeline{
+		APIVersion: "tekton.dev/v1beta1",
+		Kind:       "Pipeline",
+		Metadata:   meta.ObjectMeta{Name: pipelineName, Namespace: o.namespace},
+	}
+	pipeline.Spec.Params = []tektonParam{{
+		Name:    pipelineImageRepoParam,
+		Default: fmt.Sprintf("image-registry.openshift-image-registry.svc:5000/%s/%s", o.namespace, steps.PipelineImageStream),
+	}}
+	pipeline.Spec.Workspaces = []tektonWorkspaceDeclaration{{Name: pipelineWorkspaceName}}
+
+	rawSteps := map[string]api.StepConfiguration{}
+	for _, rawStep := range o.buildConfig.RawSteps {
+		rawSteps[rawStep.As] = rawStep
+	}
+
+	order, runAfters := tektonTaskOrder(graph)
+
+	var manifests []interface{}
+	manifests = append(manifests, pipelineWorkspacePVC(pipelineName, o.namespace))
+
+	for _, name := range order {
+		rawStep, ok := rawSteps[name]
+		if !ok {
+			return fmt.Errorf("step %q has no matching raw_steps entry to translate into a Tekton Task", name)
+		}
+		taskResource, err := tektonTaskForStep(rawStep)
+		if err != nil {
+			return err
+		}
+		taskResource.Metadata.Namespace = o.namespace
+		manifests = append(manifests, taskResource)
+
+		pipelineTask := tektonTask{Name: name, RunAfter: runAfters[name]}
+		pipelineTask.TaskRef.Name = name
+		for _, param := range taskResource.Spec.Params {
+			pipelineTask.Params = append(pipelineTask.Params, tektonParam{Name: param.Name, Value: fmt.Sprintf("$(params.%s)", param.Name)})
+		}
+		for _, workspace := range taskResource.Spec.Workspaces {
+			pipelineTask.Workspaces = append(pipelineTask.Workspaces, tektonWorkspaceBinding{Name: workspace.Name, Workspace: pipelineWorkspaceName})
+		}
+		pipeline.Spec.Tasks = append(pipeline.Spec.Tasks, pipelineTask)
+	}
+	manifests = append(manifests, pipeline)
+
+	run := tektonPipelineRun{
+		APIVersion: "tekton.dev/v1beta1",
+		Kind:       "PipelineRun",
+		Metadata:   meta.ObjectMeta{Name: fmt.Sprintf("%s-run", pipelineName), Namespace: o.namespace},
+	}
+	run.Spec.PipelineRef.Name = pipelineName
+	run.Spec.Params = []tektonParam{{Name: pipelineImageRepoParam, Value: pipeline.Spec.Params[0].Default}}
+	run.Spec.Workspaces = []tektonWorkspaceBinding{{
+		Name:                  pipelineWorkspaceName,
+		PersistentVolumeClaim: &tektonPVCWorkspaceSource{ClaimName: fmt.Sprintf("%s-workspace", pipelineName)},
+	}}
+	manifests = append(manifests, run)
+
+	if o.dry {
+		for _, manifest := range manifests {
+			encoded, err := json.MarshalIndent(manifest, "", "  ")
+			if err != nil {
+				return fmt.Errorf("could not marshal tekton manifest: %v", err)
+			}
+			fmt.Println(string(encoded))
+		}
+		return nil
+	}
+
+	for _, manifest := range manifests {
+		encoded, err := json.Marshal(manifest)
+		if err != nil {
+			return fmt.Errorf("could not marshal tekton manifest: %v", err)
+		}
+		if err := o.kubectlApply(encoded); err != nil {
+			return fmt.Errorf("could not apply tekton manifest: %v", err)
+		}
+	}
+
+	return o.streamPipelineRunLogs(o.namespace, run.Metadata.Name)
+}
+
+// tektonTaskOrder walks the build graph and returns the steps in an order consistent with their
+// dependencies, along with every parent name seen for each step along the way. A step depending on
+// two earlier steps (a diamond) must keep both runAfter edges, so re-visiting an already-seen node
+// still needs to contribute its parent name even though the walk doesn't recurse into that node's
+// children again.
+func tektonTaskOrder(graph []*api.StepNode) (order []string, runAfters map[string][]string) {
+	runAfters = map[string][]string{}
+	visited := map[string]bool{}
+	var visit func(node *api.StepNode, parent string)
+	visit = func(node *api.StepNode, parent string) {
+		name := node.Step.Name()
+		if parent != "" {
+			runAfters[name] = append(runAfters[name], parent)
+		}
+		if visited[name] {
+			return
+		}
+		visited[name] = true
+
+		for _, child := range node.Children {
+			visit(child, name)
+		}
+		order = append(order, name)
+	}
+	for _, root := range graph {
+		visit(root, "")
+	}
+	// each step was appended after everything that depends on it, so the reversed post-order
+	// lists every step after all of its parents
+	for i, j := 0, len(order)-1; i < j; i, j = i+1, j-1 {
+		order[i], order[j] = order[j], order[i]
+	}
+	return order, runAfters
+}
+
+// tektonTaskForStep translates one raw step configuration into a standalone Tekton Task that does
+// the same work its in-process pkg/steps counterpart would have done via the OpenShift API,
+// through the `oc` CLI instead, since a Task's containers have no in-cluster client of their own.
+func tektonTaskForStep(rawStep api.StepConfiguration) (tektonTaskResource, error) {
+	task := tektonTaskResource{
+		APIVersion: "tekton.dev/v1beta1",
+		Kind:       "Task",
+		Metadata:   meta.ObjectMeta{Name: rawStep.As},
+	}
+
+	switch {
+	case rawStep.InputImageTagStepConfiguration != nil:
+		tag := rawStep.InputImageTagStepConfiguration.To
+		task.Spec.Params = []tektonParam{{Name: pipelineImageRepoParam}}
+		task.Spec.Steps = []tektonContainerStep{{
+			Name:    "mirror",
+			Image:   tektonCLIImage,
+			Command: []string{"oc"},
+			Args:    []string{"tag", tag, fmt.Sprintf("$(params.%s):%s", pipelineImageRepoParam, tag)},
+		}}
+	case rawStep.RPMServeStepConfiguration != nil:
+		task.Spec.Steps = []tektonContainerStep{{
+			Name:    "serve",
+			Image:   tektonCLIImage,
+			Command: []string{"oc"},
+			Args:    []string{"expose", "service", steps.RPMRepoName},
+		}}
+	case rawStep.TemplateStepConfiguration != nil:
+		task.Spec.Params = []tektonParam{{Name: pipelineImageRepoParam}}
+		task.Spec.Workspaces = []tektonWorkspaceDeclaration{{Name: pipelineWorkspaceName}}
+		task.Spec.Steps = []tektonContainerStep{{
+			Name:    "run-template",
+			Image:   tektonCLIImage,
+			Command: []string{"/bin/sh", "-c"},
+			Args:    []string{fmt.Sprintf("oc process -f %s | oc apply -f -", rawStep.TemplateStepConfiguration.Name)},
+		}}
+	default:
+		return tektonTaskResource{}, fmt.Errorf("step %q does not set a recognized step type", rawStep.As)
+	}
+	return task, nil
+}
+
+// pipelineWorkspacePVC backs the shared "pipeline" workspace that the Tasks declaring it mount to
+// pass files between steps - the Tekton equivalent of the scratch space steps would otherwise share within
+// the job namespace itself.
+func pipelineWorkspacePVC(pipelineName, namespace string) coreapi.PersistentVolumeClaim {
+	return coreapi.PersistentVolumeClaim{
+		TypeMeta: meta.TypeMeta{APIVersion: "v1", Kind: "PersistentVolumeClaim"},
+		ObjectMeta: meta.ObjectMeta{
+			Name:      fmt.Sprintf("%s-workspace", pipelineName),
+			Namespace: namespace,
+		},
+		Spec: coreapi.PersistentVolumeClaimSpec{
+			AccessModes: []coreapi.PersistentVolumeAccessMode{coreapi.ReadWriteOnce},
+			Resources: coreapi.ResourceRequirements{
+				Requests: coreapi.ResourceList{coreapi.ResourceStorage: resource.MustParse("1Gi")},
+			},
+		},
+	}
+}
+
+// clusterArgs returns the --kubeconfig/--context flags ci-operator itself was given, so
+// emitted-pipeline tooling talks to the same cluster ci-operator was pointed at rather than
+// whatever context happens to be ambient in the caller's default kubeconfig.
+func (o *options) clusterArgs() []string {
+	var args []string
+	if o.kubeconfig != "" {
+		args = append(args, "--kubeconfig", o.kubeconfig)
+	}
+	if o.context != "" {
+		args = append(args, "--context", o.context)
+	}
+	return args
+}
+
+func (o *options) kubectlApply(manifest []byte) error {
+	args := append([]string{"apply", "-f", "-"}, o.clusterArgs()...)
+	cmd := exec.Command("kubectl", args...)
+	cmd.Stdin = strings.NewReader(string(manifest))
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
+
+// streamPipelineRunLogs follows the logs of the PipelineRun via `tkn pipelinerun logs -f`, which
+// already knows how to fan out across the Tekton TaskRun pods a PipelineRun creates.
+func (o *options) streamPipelineRunLogs(namespace, name string) error {
+	log.Printf("Streaming logs for PipelineRun %s/%s", namespace, name)
+	args := append([]string{"pipelinerun", "logs", name, "-n", namespace, "-f"}, o.clusterArgs()...)
+	cmd := exec.Command("tkn", args...)
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	cmd.Stderr = os.Stderr
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	scanner := bufio.NewScanner(stdout)
+	for scanner.Scan() {
+		fmt.Println(scanner.Text())
+	}
+	return cmd.Wait()
+}
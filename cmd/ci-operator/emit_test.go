@@ -0,0 +1,72 @@
+package main
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/openshift/ci-operator/pkg/api"
+)
+
+// fakeStep is a minimal api.Step used to build StepNode graphs without needing a full FromConfig.
+type fakeStep struct {
+	name string
+}
+
+func (s *fakeStep) Name() string       { return s.name }
+func (s *fakeStep) Requires() []string { return nil }
+func (s *fakeStep) Cluster() string    { return "" }
+func (s *fakeStep) Run(dry bool) error { return nil }
+
+func node(name string, children ...*api.StepNode) *api.StepNode {
+	return &api.StepNode{Step: &fakeStep{name: name}, Children: children}
+}
+
+func TestTektonTaskOrderPreservesDiamondRunAfters(t *testing.T) {
+	test := node("test")
+	buildA := node("build-a", test)
+	buildB := node("build-b", test)
+	src := node("src", buildA, buildB)
+
+	order, runAfters := tektonTaskOrder([]*api.StepNode{src})
+
+	if len(order) != 4 {
+		t.Fatalf("expected 4 steps in order, got %v", order)
+	}
+	if order[0] != "src" {
+		t.Fatalf("expected src to be emitted first, got order %v", order)
+	}
+
+	sortedRunAfter := append([]string{}, runAfters["test"]...)
+	sort.Strings(sortedRunAfter)
+	if want := []string{"build-a", "build-b"}; !reflect.DeepEqual(sortedRunAfter, want) {
+		t.Fatalf("test's runAfter = %v, want both diamond parents %v", runAfters["test"], want)
+	}
+	if got, want := runAfters["build-a"], []string{"src"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("build-a's runAfter = %v, want %v", got, want)
+	}
+	if got, want := runAfters["build-b"], []string{"src"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("build-b's runAfter = %v, want %v", got, want)
+	}
+	if _, ok := runAfters["src"]; ok {
+		t.Fatalf("src is a root and should have no runAfter, got %v", runAfters["src"])
+	}
+}
+
+func TestTektonTaskOrderLinearChain(t *testing.T) {
+	c := node("c")
+	b := node("b", c)
+	a := node("a", b)
+
+	order, runAfters := tektonTaskOrder([]*api.StepNode{a})
+
+	if want := []string{"a", "b", "c"}; !reflect.DeepEqual(order, want) {
+		t.Fatalf("order = %v, want %v", order, want)
+	}
+	if got, want := runAfters["b"], []string{"a"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("b's runAfter = %v, want %v", got, want)
+	}
+	if got, want := runAfters["c"], []string{"b"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("c's runAfter = %v, want %v", got, want)
+	}
+}
@@ -6,23 +6,32 @@ import (
 	"fmt"
 	"io/ioutil"
 	"log"
+	"net/http"
 	"os"
+	"os/signal"
 	"strings"
+	"syscall"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	batchapi "k8s.io/api/batch/v1"
 	coreapi "k8s.io/api/core/v1"
 	rbacapi "k8s.io/api/rbac/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/wait"
+	batchclientset "k8s.io/client-go/kubernetes/typed/batch/v1"
 	coreclientset "k8s.io/client-go/kubernetes/typed/core/v1"
 	rbacclientset "k8s.io/client-go/kubernetes/typed/rbac/v1"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
 
 	imageapi "github.com/openshift/api/image/v1"
 	projectapi "github.com/openshift/api/project/v1"
 	routeapi "github.com/openshift/api/route/v1"
+	buildclientset "github.com/openshift/client-go/build/clientset/versioned/typed/build/v1"
 	imageclientset "github.com/openshift/client-go/image/clientset/versioned/typed/image/v1"
 	"github.com/openshift/client-go/project/clientset/versioned"
 	routeclientset "github.com/openshift/client-go/route/clientset/versioned/typed/route/v1"
@@ -39,21 +48,83 @@ func bindOptions() *options {
 	flag.BoolVar(&opt.dry, "dry-run", true, "Do not contact the API server.")
 	flag.StringVar(&opt.writeParams, "write-params", "", "If set write an env-compatible file with the output of the job.")
 	flag.DurationVar(&opt.idleCleanupDuration, "delete-when-idle", opt.idleCleanupDuration, "If no pod is running for longer than this interval, delete the namespace.")
+	flag.Int32Var(&opt.cleanupBackoffLimit, "cleanup-backoff-limit", 2, "Number of retries the idle-cleanup Job gets before it is considered failed.")
+	flag.DurationVar(&opt.cleanupActiveDeadline, "cleanup-active-deadline", 12*time.Hour, "Maximum amount of time the idle-cleanup Job is allowed to run before it is killed.")
+	flag.BoolVar(&opt.teardown, "teardown", false, "Run the teardown steps for the given configuration and exit, without executing the build graph.")
+	flag.StringVar(&opt.kubeconfig, "kubeconfig", "", "Path to a kubeconfig file to use instead of in-cluster configuration.")
+	flag.StringVar(&opt.context, "context", "", "Context within the kubeconfig to use as the default cluster, defaults to the kubeconfig's current context.")
+	flag.StringVar(&opt.asUser, "as", "", "Username to impersonate for all requests.")
+	flag.Var(&opt.asGroups, "as-group", "Group to impersonate for all requests. May be specified multiple times.")
+	flag.StringVar(&opt.junitPath, "junit-path", "", "If set, write a JUnit XML report of step results to this path, for consumption by Prow/Spyglass.")
+	flag.StringVar(&opt.resultsPath, "results-path", "", "If set, write a JSON-lines report of step results to this path.")
+	flag.StringVar(&opt.metricsListen, "metrics-listen", "", "If set, serve Prometheus metrics about step durations and outcomes on this address, e.g. :9090.")
+	flag.StringVar(&opt.namespacePool, "namespace-pool", "", "If set, lease a pre-warmed namespace labelled ci.openshift.io/pool=<name> instead of creating a fresh project.")
+	flag.StringVar(&opt.emit, "emit", "", "If set, serialize the build graph as a declarative pipeline instead of executing it in-process. Only \"tekton\" is implemented; \"argo\" and \"kbatch\" are reserved names that currently fail with a not-implemented error.")
 	return opt
 }
 
+const (
+	// poolLabel marks a namespace as belonging to a namespace pool.
+	poolLabel = "ci.openshift.io/pool"
+	// leasedByAnnotation records which job currently holds a pooled namespace.
+	leasedByAnnotation = "ci.openshift.io/leased-by"
+	// leasedAtAnnotation records when a pooled namespace was leased, for TTL reaping by the pool controller.
+	leasedAtAnnotation = "ci.openshift.io/leased-at"
+)
+
+// stringSliceFlag collects repeated occurrences of a flag into a slice.
+type stringSliceFlag []string
+
+func (s *stringSliceFlag) String() string {
+	return strings.Join(*s, ",")
+}
+
+func (s *stringSliceFlag) Set(value string) error {
+	*s = append(*s, value)
+	return nil
+}
+
 type options struct {
 	rawBuildConfig string
 	dry            bool
 	writeParams    string
 
-	namespace           string
-	baseNamespace       string
-	idleCleanupDuration time.Duration
+	namespace             string
+	baseNamespace         string
+	idleCleanupDuration   time.Duration
+	cleanupBackoffLimit   int32
+	cleanupActiveDeadline time.Duration
+	teardown              bool
+
+	kubeconfig string
+	context    string
+	asUser     string
+	asGroups   stringSliceFlag
+
+	junitPath     string
+	resultsPath   string
+	metricsListen string
 
-	buildConfig   *api.ReleaseBuildConfiguration
-	jobSpec       *steps.JobSpec
-	clusterConfig *rest.Config
+	namespacePool string
+
+	emit string
+
+	buildConfig    *api.ReleaseBuildConfiguration
+	jobSpec        *steps.JobSpec
+	clusterConfigs map[string]*rest.Config
+}
+
+// clusterConfig returns the *rest.Config that should be used for the named
+// cluster, falling back to the default cluster if the step did not request
+// one explicitly.
+func (o *options) clusterConfig(cluster string) *rest.Config {
+	if cluster == "" {
+		cluster = api.ClusterDefault
+	}
+	if config, ok := o.clusterConfigs[cluster]; ok {
+		return config
+	}
+	return o.clusterConfigs[api.ClusterDefault]
 }
 
 func (o *options) Validate() error {
@@ -83,31 +154,69 @@ func (o *options) Complete() error {
 		return fmt.Errorf("malformed build configuration: %v", err)
 	}
 
-	clusterConfig, err := loadClusterConfig()
+	clusterConfigs, err := o.loadClusterConfigs()
 	if err != nil {
 		return fmt.Errorf("failed to load cluster config: %v", err)
 	}
-	o.clusterConfig = clusterConfig
+	o.clusterConfigs = clusterConfigs
 
 	return nil
 }
 
-// loadClusterConfig loads connection configuration
-// for the cluster we're deploying to. We prefer to
-// use in-cluster configuration if possible, but will
-// fall back to using default rules otherwise.
-func loadClusterConfig() (*rest.Config, error) {
-	clusterConfig, err := rest.InClusterConfig()
-	if err == nil {
-		return clusterConfig, nil
+// loadClusterConfigs loads connection configuration for every cluster the build steps may
+// execute on. The default cluster (used when a step does not set `cluster:`) prefers in-cluster
+// configuration if possible, honoring --kubeconfig/--context/--as/--as-group overrides otherwise;
+// any other cluster named by a step's `cluster:` field is resolved as a context of the same
+// kubeconfig, so a single `oc login`-populated kubeconfig can address a whole fleet of clusters.
+func (o *options) loadClusterConfigs() (map[string]*rest.Config, error) {
+	overrides := &clientcmd.ConfigOverrides{
+		CurrentContext: o.context,
+		AuthInfo:       clientcmdapi.AuthInfo{Impersonate: o.asUser, ImpersonateGroups: o.asGroups},
+	}
+
+	defaultConfig, err := loadClusterConfig(o.kubeconfig, overrides)
+	if err != nil {
+		return nil, err
+	}
+
+	configs := map[string]*rest.Config{api.ClusterDefault: defaultConfig}
+
+	for _, cluster := range o.buildConfig.StepClusters() {
+		if cluster == "" || cluster == api.ClusterDefault {
+			continue
+		}
+		clusterOverrides := *overrides
+		clusterOverrides.CurrentContext = cluster
+		config, err := loadClusterConfig(o.kubeconfig, &clusterOverrides)
+		if err != nil {
+			return nil, fmt.Errorf("could not load configuration for cluster %q: %v", cluster, err)
+		}
+		configs[cluster] = config
+	}
+
+	return configs, nil
+}
+
+// loadClusterConfig loads connection configuration for a single cluster. We prefer to use
+// in-cluster configuration if possible and no explicit overrides were requested, but will fall
+// back to kubeconfig-based loading (optionally from a specific path and context) otherwise.
+func loadClusterConfig(kubeconfig string, overrides *clientcmd.ConfigOverrides) (*rest.Config, error) {
+	if kubeconfig == "" && overrides.CurrentContext == "" && overrides.AuthInfo.Impersonate == "" && len(overrides.AuthInfo.ImpersonateGroups) == 0 {
+		if clusterConfig, err := rest.InClusterConfig(); err == nil {
+			return clusterConfig, nil
+		}
 	}
 
-	credentials, err := clientcmd.NewDefaultClientConfigLoadingRules().Load()
+	loadingRules := clientcmd.NewDefaultClientConfigLoadingRules()
+	if kubeconfig != "" {
+		loadingRules.ExplicitPath = kubeconfig
+	}
+	credentials, err := loadingRules.Load()
 	if err != nil {
 		return nil, fmt.Errorf("could not load credentials from config: %v", err)
 	}
 
-	clusterConfig, err = clientcmd.NewDefaultClientConfig(*credentials, &clientcmd.ConfigOverrides{}).ClientConfig()
+	clusterConfig, err := clientcmd.NewDefaultClientConfig(*credentials, overrides).ClientConfig()
 	if err != nil {
 		return nil, fmt.Errorf("could not load client configuration: %v", err)
 	}
@@ -120,46 +229,73 @@ func (o *options) Run() error {
 		log.Printf("Ran for %s", time.Now().Sub(start).Truncate(time.Second))
 	}()
 	var is *imageapi.ImageStream
-	if !o.dry {
-		projectGetter, err := versioned.NewForConfig(o.clusterConfig)
+	// --teardown only cleans up an existing run's resources, and --emit only serializes the build
+	// graph to a declarative pipeline for some other cluster to run later - neither should
+	// provision a namespace, idle-cleanup Job or pipeline ImageStream of its own.
+	if !o.dry && !o.teardown && o.emit == "" {
+		coreClient, err := coreclientset.NewForConfig(o.clusterConfig(api.ClusterDefault))
 		if err != nil {
-			return fmt.Errorf("could not get project client for cluster config: %v", err)
+			return fmt.Errorf("could not get core client for cluster config: %v", err)
 		}
 
-		log.Printf("Creating namespace %s", o.namespace)
-		for {
-			project, err := projectGetter.ProjectV1().ProjectRequests().Create(&projectapi.ProjectRequest{
-				ObjectMeta: meta.ObjectMeta{
-					Name: o.namespace,
-				},
-			})
-			if err != nil && !errors.IsAlreadyExists(err) {
-				return fmt.Errorf("could not set up namespace for test: %v", err)
+		if o.namespacePool != "" {
+			namespace, err := o.leaseNamespace(coreClient)
+			if err != nil {
+				return fmt.Errorf("could not lease namespace from pool %q: %v", o.namespacePool, err)
 			}
+			o.namespace = namespace
+			o.jobSpec.SetNamespace(o.namespace)
+
+			buildClient, err := buildclientset.NewForConfig(o.clusterConfig(api.ClusterDefault))
 			if err != nil {
-				project, err = projectGetter.ProjectV1().Projects().Get(o.namespace, meta.GetOptions{})
+				return fmt.Errorf("could not get build client for cluster config: %v", err)
+			}
+			defer func() {
+				if err := o.resetPooledNamespace(coreClient, buildClient); err != nil {
+					log.Printf("error: Failed to reset pooled namespace %s: %v", o.namespace, err)
+				}
+			}()
+		} else {
+			projectGetter, err := versioned.NewForConfig(o.clusterConfig(api.ClusterDefault))
+			if err != nil {
+				return fmt.Errorf("could not get project client for cluster config: %v", err)
+			}
+
+			log.Printf("Creating namespace %s", o.namespace)
+			for {
+				project, err := projectGetter.ProjectV1().ProjectRequests().Create(&projectapi.ProjectRequest{
+					ObjectMeta: meta.ObjectMeta{
+						Name: o.namespace,
+					},
+				})
+				if err != nil && !errors.IsAlreadyExists(err) {
+					return fmt.Errorf("could not set up namespace for test: %v", err)
+				}
 				if err != nil {
-					if errors.IsNotFound(err) {
-						continue
+					project, err = projectGetter.ProjectV1().Projects().Get(o.namespace, meta.GetOptions{})
+					if err != nil {
+						if errors.IsNotFound(err) {
+							continue
+						}
+						return fmt.Errorf("cannot retrieve test namespace: %v", err)
 					}
-					return fmt.Errorf("cannot retrieve test namespace: %v", err)
 				}
+				if project.Status.Phase == coreapi.NamespaceTerminating {
+					log.Println("Waiting for namespace to finish terminating before creating another")
+					time.Sleep(3 * time.Second)
+					continue
+				}
+				break
 			}
-			if project.Status.Phase == coreapi.NamespaceTerminating {
-				log.Println("Waiting for namespace to finish terminating before creating another")
-				time.Sleep(3 * time.Second)
-				continue
-			}
-			break
-		}
 
-		if o.idleCleanupDuration > 0 {
-			if err := o.createNamespaceCleanupPod(); err != nil {
-				return err
+			if o.idleCleanupDuration > 0 {
+				if err := o.createNamespaceCleanupJob(); err != nil {
+					return err
+				}
 			}
 		}
 
-		imageGetter, err := imageclientset.NewForConfig(o.clusterConfig)
+		imageGetter, err := imageclientset.NewForConfig(o.clusterConfig(api.ClusterDefault))
 		if err != nil {
 			return fmt.Errorf("could not get image client for cluster config: %v", err)
 		}
@@ -189,17 +325,56 @@ func (o *options) Run() error {
 		}
 	}
 
-	buildSteps, err := steps.FromConfig(o.buildConfig, o.jobSpec, o.clusterConfig)
+	buildSteps, err := steps.FromConfig(o.buildConfig, o.jobSpec, o.clusterConfigs)
 	if err != nil {
 		return fmt.Errorf("failed to generate steps from config: %v", err)
 	}
+	graph := api.BuildGraph(buildSteps)
+
+	interrupts := make(chan os.Signal, 1)
+	signal.Notify(interrupts, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		sig := <-interrupts
+		log.Printf("Received %s, running teardown steps before exiting ...", sig)
+		if err := steps.TeardownGraph(graph).Run(); err != nil {
+			log.Printf("error: Failed to tear down steps: %v", err)
+		}
+		os.Exit(1)
+	}()
+
+	if o.teardown {
+		return steps.TeardownGraph(graph).Run()
+	}
+
+	if o.emit != "" {
+		return o.emitPipeline(graph)
+	}
+
+	if o.metricsListen != "" {
+		http.Handle("/metrics", promhttp.Handler())
+		go func() {
+			if err := http.ListenAndServe(o.metricsListen, nil); err != nil {
+				log.Printf("error: Metrics server exited: %v", err)
+			}
+		}()
+	}
 
-	if err := steps.Run(api.BuildGraph(buildSteps), o.dry); err != nil {
+	var sinks []steps.ResultSink
+	if o.junitPath != "" {
+		sinks = append(sinks, steps.NewJUnitSink(o.junitPath))
+	}
+	if o.resultsPath != "" {
+		sinks = append(sinks, steps.NewJSONSink(o.resultsPath))
+	}
+	sink := steps.MultiSink(sinks)
+
+	results, err := steps.Run(graph, o.dry, sink)
+	if err != nil {
 		return err
 	}
 
 	if len(o.writeParams) > 0 {
-		if err := o.writeParameters(o.writeParams, is); err != nil {
+		if err := o.writeParameters(o.writeParams, is, results); err != nil {
 			return fmt.Errorf("failed to write parameters: %v", err)
 		}
 	}
@@ -241,13 +416,21 @@ func main() {
 	}
 }
 
-func (o *options) writeParameters(path string, is *imageapi.ImageStream) error {
+func (o *options) writeParameters(path string, is *imageapi.ImageStream, results []steps.StepResult) error {
 	log.Printf("Writing parameters to %s", path)
 	var params []string
 
 	params = append(params, fmt.Sprintf("JOB_NAME=%q", o.jobSpec.Job))
 	params = append(params, fmt.Sprintf("NAMESPACE=%q", o.namespace))
 
+	var ran []string
+	for _, result := range results {
+		if result.Phase == steps.PhaseSucceeded {
+			ran = append(ran, result.Name)
+		}
+	}
+	params = append(params, fmt.Sprintf("JOB_STEPS_RAN=%q", strings.Join(ran, ",")))
+
 	if tagConfig := o.buildConfig.ReleaseTagConfiguration; tagConfig != nil {
 		registry := "REGISTRY"
 		if is != nil {
@@ -270,7 +453,7 @@ func (o *options) writeParameters(path string, is *imageapi.ImageStream) error {
 		if o.dry {
 			params = append(params, "RPM_REPO=\"\"")
 		} else {
-			routeclient, err := routeclientset.NewForConfig(o.clusterConfig)
+			routeclient, err := routeclientset.NewForConfig(o.clusterConfig(api.ClusterDefault))
 			if err != nil {
 				return fmt.Errorf("could not get route client for cluster config: %v", err)
 			}
@@ -301,18 +484,96 @@ func (o *options) writeParameters(path string, is *imageapi.ImageStream) error {
 	return nil
 }
 
-// createNamespaceCleanupPod creates a pod that deletes the job namespace if no other run-once pods are running
-// for more than idleCleanupDuration.
-func (o *options) createNamespaceCleanupPod() error {
+// leaseNamespace waits for a namespace in the pool to be free and leases it by annotating it with
+// leasedByAnnotation/leasedAtAnnotation. The annotation update is retried against conflicts so that
+// two concurrent ci-operator invocations racing for the same namespace don't both win the lease.
+func (o *options) leaseNamespace(client coreclientset.CoreV1Interface) (string, error) {
+	var leased string
+	err := wait.PollImmediate(5*time.Second, 30*time.Minute, func() (bool, error) {
+		namespaces, err := client.Namespaces().List(meta.ListOptions{
+			LabelSelector: fmt.Sprintf("%s=%s", poolLabel, o.namespacePool),
+		})
+		if err != nil {
+			return false, err
+		}
+		for _, ns := range namespaces.Items {
+			if _, leased := ns.Annotations[leasedByAnnotation]; leased {
+				continue
+			}
+			candidate := ns.DeepCopy()
+			if candidate.Annotations == nil {
+				candidate.Annotations = map[string]string{}
+			}
+			candidate.Annotations[leasedByAnnotation] = o.jobSpec.Job
+			candidate.Annotations[leasedAtAnnotation] = time.Now().Format(time.RFC3339)
+			if _, err := client.Namespaces().Update(candidate); err != nil {
+				if errors.IsConflict(err) {
+					// someone else won the race for this namespace, try the next one
+					continue
+				}
+				return false, err
+			}
+			leased = candidate.Name
+			return true, nil
+		}
+		log.Printf("Waiting for a free namespace in pool %q ...", o.namespacePool)
+		return false, nil
+	})
+	if err != nil {
+		return "", err
+	}
+	log.Printf("Leased namespace %s from pool %q", leased, o.namespacePool)
+	return leased, nil
+}
+
+// resetPooledNamespace clears out everything a run left behind in a leased namespace - builds,
+// pods and secrets - while keeping imagestreams, then releases the lease so the pool controller
+// can offer the namespace to the next run. This is far cheaper than deleting and recreating the
+// project, which is the whole point of the pool.
+func (o *options) resetPooledNamespace(client coreclientset.CoreV1Interface, buildClient buildclientset.BuildV1Interface) error {
+	log.Printf("Resetting namespace %s and returning it to pool %q", o.namespace, o.namespacePool)
+
+	if err := buildClient.Builds(o.namespace).DeleteCollection(&meta.DeleteOptions{}, meta.ListOptions{}); err != nil && !errors.IsNotFound(err) {
+		return fmt.Errorf("could not clear builds: %v", err)
+	}
+	if err := client.Pods(o.namespace).DeleteCollection(&meta.DeleteOptions{}, meta.ListOptions{}); err != nil && !errors.IsNotFound(err) {
+		return fmt.Errorf("could not clear pods: %v", err)
+	}
+	if err := client.Secrets(o.namespace).DeleteCollection(&meta.DeleteOptions{}, meta.ListOptions{}); err != nil && !errors.IsNotFound(err) {
+		return fmt.Errorf("could not clear secrets: %v", err)
+	}
+
+	ns, err := client.Namespaces().Get(o.namespace, meta.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("could not retrieve namespace to release lease: %v", err)
+	}
+	delete(ns.Annotations, leasedByAnnotation)
+	delete(ns.Annotations, leasedAtAnnotation)
+	delete(ns.Annotations, "ci.openshift.io/idle-since")
+	if _, err := client.Namespaces().Update(ns); err != nil {
+		return fmt.Errorf("could not release lease on namespace: %v", err)
+	}
+	return nil
+}
+
+// createNamespaceCleanupJob creates a Job that deletes the job namespace if no other run-once pods are running
+// for more than idleCleanupDuration. Unlike a bare Pod, the Job gives us a completion status, a bounded number
+// of retries via backoffLimit and an activeDeadlineSeconds, and ttlSecondsAfterFinished means we don't have to
+// come back and sweep up the cleanup workload itself once it's done.
+func (o *options) createNamespaceCleanupJob() error {
 	log.Printf("Namespace will be deleted after %s of idle time", o.idleCleanupDuration)
-	client, err := coreclientset.NewForConfig(o.clusterConfig)
+	client, err := coreclientset.NewForConfig(o.clusterConfig(api.ClusterDefault))
 	if err != nil {
 		return fmt.Errorf("could not get image client for cluster config: %v", err)
 	}
-	rbacClient, err := rbacclientset.NewForConfig(o.clusterConfig)
+	rbacClient, err := rbacclientset.NewForConfig(o.clusterConfig(api.ClusterDefault))
 	if err != nil {
 		return fmt.Errorf("could not get image client for cluster config: %v", err)
 	}
+	batchClient, err := batchclientset.NewForConfig(o.clusterConfig(api.ClusterDefault))
+	if err != nil {
+		return fmt.Errorf("could not get batch client for cluster config: %v", err)
+	}
 
 	if _, err := client.ServiceAccounts(o.namespace).Create(&coreapi.ServiceAccount{
 		ObjectMeta: meta.ObjectMeta{
@@ -335,67 +596,82 @@ func (o *options) createNamespaceCleanupPod() error {
 	}
 
 	grace := int64(30)
-	deadline := int64(12 * time.Hour / time.Second)
-	if _, err := client.Pods(o.namespace).Create(&coreapi.Pod{
+	deadline := int64(o.cleanupActiveDeadline / time.Second)
+	ttl := int32(10 * time.Minute / time.Second)
+	backoffLimit := o.cleanupBackoffLimit
+	if _, err := batchClient.Jobs(o.namespace).Create(&batchapi.Job{
 		ObjectMeta: meta.ObjectMeta{
 			Name: "cleanup-when-idle",
 		},
-		Spec: coreapi.PodSpec{
-			ActiveDeadlineSeconds:         &deadline,
-			RestartPolicy:                 coreapi.RestartPolicyNever,
-			TerminationGracePeriodSeconds: &grace,
-			ServiceAccountName:            "cleanup",
-			Containers: []coreapi.Container{
-				{
-					Name:  "cleanup",
-					Image: "openshift/origin-cli:latest",
-					Env: []coreapi.EnvVar{
-						{
-							Name:      "NAMESPACE",
-							ValueFrom: &coreapi.EnvVarSource{FieldRef: &coreapi.ObjectFieldSelector{FieldPath: "metadata.namespace"}},
-						},
+		Spec: batchapi.JobSpec{
+			BackoffLimit:            &backoffLimit,
+			ActiveDeadlineSeconds:   &deadline,
+			TTLSecondsAfterFinished: &ttl,
+			Template: coreapi.PodTemplateSpec{
+				ObjectMeta: meta.ObjectMeta{
+					Name: "cleanup-when-idle",
+				},
+				Spec: coreapi.PodSpec{
+					RestartPolicy:                 coreapi.RestartPolicyNever,
+					TerminationGracePeriodSeconds: &grace,
+					ServiceAccountName:            "cleanup",
+					Containers: []coreapi.Container{
 						{
-							Name:  "WAIT",
-							Value: fmt.Sprintf("%d", int(o.idleCleanupDuration.Seconds())),
-						},
-					},
-					Command: []string{"/bin/bash", "-c"},
-					Args: []string{`
-						#!/bin/bash
-						set -euo pipefail
-
-						function cleanup() {
-							set +e
-							oc delete project ${NAMESPACE}
-						}
-
-						trap 'kill $(jobs -p); echo "Pod deleted, deleting project ..."; exit 1' TERM
-						trap cleanup EXIT
-
-						echo "Waiting for all running pods to terminate (max idle ${WAIT}s) ..."
-						count=0
-						while true; do
-							alive="$( oc get pods --template '{{ range .items }}{{ if and (not (eq .metadata.name "cleanup-when-idle")) (eq .spec.restartPolicy "Never") (or (eq .status.phase "Pending") (eq .status.phase "Running") (eq .status.phase "Unknown")) }} {{ .metadata.name }}{{ end }}{{ end }}' )"
-							if [[ -n "${alive}" ]]; then
+							Name:  "cleanup",
+							Image: "openshift/origin-cli:latest",
+							Env: []coreapi.EnvVar{
+								{
+									Name:      "NAMESPACE",
+									ValueFrom: &coreapi.EnvVarSource{FieldRef: &coreapi.ObjectFieldSelector{FieldPath: "metadata.namespace"}},
+								},
+								{
+									Name:  "WAIT",
+									Value: fmt.Sprintf("%d", int(o.idleCleanupDuration.Seconds())),
+								},
+								{
+									Name:      "POD_NAME",
+									ValueFrom: &coreapi.EnvVarSource{FieldRef: &coreapi.ObjectFieldSelector{FieldPath: "metadata.name"}},
+								},
+							},
+							Command: []string{"/bin/bash", "-c"},
+							Args: []string{`
+								#!/bin/bash
+								set -euo pipefail
+
+								function cleanup() {
+									set +e
+									oc delete project ${NAMESPACE}
+								}
+
+								trap 'kill $(jobs -p); echo "Pod deleted, deleting project ..."; exit 1' TERM
+								trap cleanup EXIT
+
+								echo "Waiting for all running pods to terminate (max idle ${WAIT}s) ..."
 								count=0
-								sleep ${WAIT} & wait
-								continue
-							fi
-							if [[ "${count}" -lt 1 ]]; then
-								count+=1
-								sleep ${WAIT} & wait
-								continue
-							fi
-							echo "No pods running for more than ${WAIT}s, deleting project ..."
-							exit 0
-						done
-						`,
+								while true; do
+									alive="$( oc get pods --template '{{ range .items }}{{ if and (not (eq .metadata.name "'"${POD_NAME}"'")) (eq .spec.restartPolicy "Never") (or (eq .status.phase "Pending") (eq .status.phase "Running") (eq .status.phase "Unknown")) }} {{ .metadata.name }}{{ end }}{{ end }}' )"
+									if [[ -n "${alive}" ]]; then
+										count=0
+										sleep ${WAIT} & wait
+										continue
+									fi
+									if [[ "${count}" -lt 1 ]]; then
+										count+=1
+										sleep ${WAIT} & wait
+										continue
+									fi
+									echo "No pods running for more than ${WAIT}s, deleting project ..."
+									exit 0
+								done
+								`,
+							},
+						},
 					},
 				},
 			},
 		},
 	}); err != nil && !errors.IsAlreadyExists(err) {
-		return fmt.Errorf("could not create pod for cleanup: %v", err)
+		return fmt.Errorf("could not create job for cleanup: %v", err)
 	}
 	return nil
-}
\ No newline at end of file
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"testing"
+
+	coreapi "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clienttesting "k8s.io/client-go/testing"
+
+	fakeclientset "k8s.io/client-go/kubernetes/fake"
+
+	"github.com/openshift/ci-operator/pkg/steps"
+)
+
+func TestLeaseNamespaceRetriesOnConflict(t *testing.T) {
+	pool := "e2e"
+	lost := &coreapi.Namespace{
+		ObjectMeta: meta.ObjectMeta{
+			Name:   "ci-op-pool-e2e-1",
+			Labels: map[string]string{poolLabel: pool},
+		},
+	}
+	won := &coreapi.Namespace{
+		ObjectMeta: meta.ObjectMeta{
+			Name:   "ci-op-pool-e2e-2",
+			Labels: map[string]string{poolLabel: pool},
+		},
+	}
+	client := fakeclientset.NewSimpleClientset(lost, won)
+
+	// Simulate a concurrent ci-operator invocation winning the race for the first namespace: its
+	// Update conflicts, and leaseNamespace must move on to the next candidate in the same pass
+	// rather than erroring out or handing out the same namespace to two callers.
+	client.PrependReactor("update", "namespaces", func(action clienttesting.Action) (bool, runtime.Object, error) {
+		update := action.(clienttesting.UpdateAction)
+		if update.GetObject().(*coreapi.Namespace).Name == lost.Name {
+			return true, nil, errors.NewConflict(coreapi.Resource("namespaces"), lost.Name, nil)
+		}
+		return false, nil, nil
+	})
+
+	o := &options{namespacePool: pool, jobSpec: &steps.JobSpec{Job: "test-job"}}
+	leased, err := o.leaseNamespace(client.CoreV1())
+	if err != nil {
+		t.Fatalf("leaseNamespace returned an error: %v", err)
+	}
+	if leased != won.Name {
+		t.Fatalf("leaseNamespace returned %q, want the namespace it could actually win, %q", leased, won.Name)
+	}
+
+	updated, err := client.CoreV1().Namespaces().Get(won.Name, meta.GetOptions{})
+	if err != nil {
+		t.Fatalf("could not fetch updated namespace: %v", err)
+	}
+	if updated.Annotations[leasedByAnnotation] != "test-job" {
+		t.Fatalf("namespace was not leased by the expected job, got annotations %v", updated.Annotations)
+	}
+
+	stillFree, err := client.CoreV1().Namespaces().Get(lost.Name, meta.GetOptions{})
+	if err != nil {
+		t.Fatalf("could not fetch the namespace that lost the race: %v", err)
+	}
+	if _, leased := stillFree.Annotations[leasedByAnnotation]; leased {
+		t.Fatalf("namespace %s should not have been leased after its Update conflicted", lost.Name)
+	}
+}
+
+func TestLeaseNamespaceSkipsAlreadyLeased(t *testing.T) {
+	pool := "e2e"
+	leased := &coreapi.Namespace{
+		ObjectMeta: meta.ObjectMeta{
+			Name:        "ci-op-pool-e2e-1",
+			Labels:      map[string]string{poolLabel: pool},
+			Annotations: map[string]string{leasedByAnnotation: "someone-else"},
+		},
+	}
+	free := &coreapi.Namespace{
+		ObjectMeta: meta.ObjectMeta{
+			Name:   "ci-op-pool-e2e-2",
+			Labels: map[string]string{poolLabel: pool},
+		},
+	}
+	client := fakeclientset.NewSimpleClientset(leased, free)
+
+	o := &options{namespacePool: pool, jobSpec: &steps.JobSpec{Job: "test-job"}}
+	got, err := o.leaseNamespace(client.CoreV1())
+	if err != nil {
+		t.Fatalf("leaseNamespace returned an error: %v", err)
+	}
+	if got != free.Name {
+		t.Fatalf("leaseNamespace leased %q, want the already-free namespace %q", got, free.Name)
+	}
+}
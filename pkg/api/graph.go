@@ -0,0 +1,39 @@
+package api
+
+// StepNode is one node of the build graph: the step itself, plus the nodes that depend on it and
+// therefore must run after it.
+type StepNode struct {
+	Step     Step
+	Children []*StepNode
+}
+
+// BuildGraph arranges steps into a forest of StepNodes based on each step's Requires() names: a
+// step becomes a child of every other step it requires. Steps that require no other step in the
+// list are returned as roots.
+func BuildGraph(steps []Step) []*StepNode {
+	nodes := make(map[string]*StepNode, len(steps))
+	for _, step := range steps {
+		nodes[step.Name()] = &StepNode{Step: step}
+	}
+
+	hasParent := make(map[string]bool, len(steps))
+	for _, step := range steps {
+		child := nodes[step.Name()]
+		for _, required := range step.Requires() {
+			parent, ok := nodes[required]
+			if !ok {
+				continue
+			}
+			parent.Children = append(parent.Children, child)
+			hasParent[step.Name()] = true
+		}
+	}
+
+	var roots []*StepNode
+	for _, step := range steps {
+		if !hasParent[step.Name()] {
+			roots = append(roots, nodes[step.Name()])
+		}
+	}
+	return roots
+}
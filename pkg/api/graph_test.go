@@ -0,0 +1,76 @@
+package api
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// fakeStep is a minimal Step used to exercise BuildGraph without depending on pkg/steps.
+type fakeStep struct {
+	name     string
+	requires []string
+}
+
+func (s *fakeStep) Name() string       { return s.name }
+func (s *fakeStep) Requires() []string { return s.requires }
+func (s *fakeStep) Cluster() string    { return "" }
+func (s *fakeStep) Run(dry bool) error { return nil }
+
+func childNames(node *StepNode) []string {
+	var names []string
+	for _, child := range node.Children {
+		names = append(names, child.Step.Name())
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestBuildGraphDiamond(t *testing.T) {
+	// src has no dependencies; build-a and build-b both run after src; test runs after both,
+	// making it a diamond-shaped graph.
+	steps := []Step{
+		&fakeStep{name: "src"},
+		&fakeStep{name: "build-a", requires: []string{"src"}},
+		&fakeStep{name: "build-b", requires: []string{"src"}},
+		&fakeStep{name: "test", requires: []string{"build-a", "build-b"}},
+	}
+
+	roots := BuildGraph(steps)
+	if len(roots) != 1 || roots[0].Step.Name() != "src" {
+		t.Fatalf("expected a single root step %q, got %#v", "src", roots)
+	}
+
+	src := roots[0]
+	if got, want := childNames(src), []string{"build-a", "build-b"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("src children = %v, want %v", got, want)
+	}
+
+	testNodesSeen := 0
+	for _, child := range src.Children {
+		if got, want := childNames(child), []string{"test"}; !reflect.DeepEqual(got, want) {
+			t.Fatalf("%s children = %v, want %v", child.Step.Name(), got, want)
+		}
+		testNodesSeen++
+	}
+	if testNodesSeen != 2 {
+		t.Fatalf("expected both build steps to point at the test step, got %d parents", testNodesSeen)
+	}
+}
+
+func TestBuildGraphUnrelatedSteps(t *testing.T) {
+	steps := []Step{
+		&fakeStep{name: "a"},
+		&fakeStep{name: "b"},
+	}
+
+	roots := BuildGraph(steps)
+	var names []string
+	for _, root := range roots {
+		names = append(names, root.Step.Name())
+	}
+	sort.Strings(names)
+	if want := []string{"a", "b"}; !reflect.DeepEqual(names, want) {
+		t.Fatalf("roots = %v, want %v", names, want)
+	}
+}
@@ -0,0 +1,24 @@
+package api
+
+// Step is implemented by each node in the build graph. Concrete implementations live in
+// pkg/steps; the interface lives here so that pkg/steps can depend on pkg/api (for
+// ReleaseBuildConfiguration and friends) without creating an import cycle back from api to steps.
+type Step interface {
+	// Name identifies the step, and is how other steps refer to it in Requires().
+	Name() string
+	// Requires lists the names of the steps that must complete before this one runs.
+	Requires() []string
+	// Cluster is the name of the cluster this step should execute against, or "" for ClusterDefault.
+	Cluster() string
+	// Run executes the step. In dry-run mode it must not contact any cluster.
+	Run(dry bool) error
+}
+
+// TeardownStep is implemented by steps that have cleanup work to do when a run is interrupted or
+// finishes, instead of relying solely on namespace deletion - e.g. an image-mirror step deleting
+// its tag, an RPM-serve step deleting its route, or a template step recording an artifacts
+// ConfigMap.
+type TeardownStep interface {
+	Step
+	Teardown() error
+}
@@ -0,0 +1,65 @@
+package api
+
+// ClusterDefault is the cluster name used when a step does not set `cluster:` explicitly, i.e.
+// the cluster ci-operator itself was configured to talk to via --kubeconfig/--context.
+const ClusterDefault = "default"
+
+// ReleaseBuildConfiguration is the parsed form of the --build-config JSON payload.
+type ReleaseBuildConfiguration struct {
+	ReleaseTagConfiguration *ReleaseTagConfiguration `json:"tag_specification,omitempty"`
+	RpmBuildCommands        string                   `json:"rpm_build_commands,omitempty"`
+	RawSteps                []StepConfiguration      `json:"raw_steps,omitempty"`
+}
+
+// ReleaseTagConfiguration describes the ImageStream that tagged builds are published to.
+type ReleaseTagConfiguration struct {
+	Namespace  string `json:"namespace,omitempty"`
+	Name       string `json:"name,omitempty"`
+	NamePrefix string `json:"name_prefix,omitempty"`
+	Tag        string `json:"tag,omitempty"`
+}
+
+// StepConfiguration is the raw, declarative form of a step as read from the build-config JSON.
+type StepConfiguration struct {
+	As string `json:"as,omitempty"`
+
+	InputImageTagStepConfiguration *InputImageTagStepConfiguration `json:"input_image_tag_step,omitempty"`
+	RPMServeStepConfiguration      *RPMServeStepConfiguration      `json:"rpm_serve_step,omitempty"`
+	TemplateStepConfiguration      *TemplateStepConfiguration      `json:"template_step,omitempty"`
+
+	// Cluster optionally pins this step to a non-default cluster (see ClusterDefault), e.g. to
+	// build on a beefy build farm but run e2e tests against the actual target cluster.
+	Cluster string `json:"cluster,omitempty"`
+
+	// After lists the `as` names of steps that must complete before this one runs. It becomes
+	// this step's Step.Requires() and is what BuildGraph uses to order and tear down the graph.
+	After []string `json:"after,omitempty"`
+}
+
+// InputImageTagStepConfiguration mirrors a tag from another ImageStream into the pipeline.
+type InputImageTagStepConfiguration struct {
+	To string `json:"to,omitempty"`
+}
+
+// RPMServeStepConfiguration serves built RPMs over an exposed Route.
+type RPMServeStepConfiguration struct{}
+
+// TemplateStepConfiguration runs a template as a build step.
+type TemplateStepConfiguration struct {
+	Name string `json:"name,omitempty"`
+}
+
+// StepClusters returns the distinct non-default cluster names referenced by this configuration's
+// steps, so the caller can pre-load a *rest.Config for each one.
+func (c *ReleaseBuildConfiguration) StepClusters() []string {
+	var clusters []string
+	seen := map[string]bool{}
+	for _, step := range c.RawSteps {
+		if step.Cluster == "" || step.Cluster == ClusterDefault || seen[step.Cluster] {
+			continue
+		}
+		seen[step.Cluster] = true
+		clusters = append(clusters, step.Cluster)
+	}
+	return clusters
+}
@@ -0,0 +1,57 @@
+package steps
+
+import (
+	"fmt"
+
+	"k8s.io/client-go/rest"
+
+	"github.com/openshift/ci-operator/pkg/api"
+)
+
+// FromConfig turns a declarative build configuration into the concrete steps that will run it,
+// resolving which cluster client each step executes against from its `cluster:` field (falling
+// back to api.ClusterDefault) so build/test steps can be spread across multiple clusters.
+func FromConfig(buildConfig *api.ReleaseBuildConfiguration, jobSpec *JobSpec, clusterConfigs map[string]*rest.Config) ([]api.Step, error) {
+	var result []api.Step
+	for _, rawStep := range buildConfig.RawSteps {
+		cluster := rawStep.Cluster
+		if cluster == "" {
+			cluster = api.ClusterDefault
+		}
+		config, ok := clusterConfigs[cluster]
+		if !ok {
+			return nil, fmt.Errorf("step %q requested unknown cluster %q", rawStep.As, cluster)
+		}
+
+		switch {
+		case rawStep.InputImageTagStepConfiguration != nil:
+			result = append(result, &imageMirrorStep{
+				name:     rawStep.As,
+				tag:      rawStep.InputImageTagStepConfiguration.To,
+				cluster:  cluster,
+				requires: rawStep.After,
+				jobSpec:  jobSpec,
+				config:   config,
+			})
+		case rawStep.RPMServeStepConfiguration != nil:
+			result = append(result, &rpmServeStep{
+				name:     rawStep.As,
+				cluster:  cluster,
+				requires: rawStep.After,
+				jobSpec:  jobSpec,
+				config:   config,
+			})
+		case rawStep.TemplateStepConfiguration != nil:
+			result = append(result, &templateStep{
+				name:     rawStep.As,
+				cluster:  cluster,
+				requires: rawStep.After,
+				jobSpec:  jobSpec,
+				config:   config,
+			})
+		default:
+			return nil, fmt.Errorf("step %q does not set a recognized step type", rawStep.As)
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,62 @@
+package steps
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"k8s.io/client-go/rest"
+
+	"github.com/openshift/ci-operator/pkg/api"
+)
+
+func TestFromConfigBuildsGraphEdges(t *testing.T) {
+	const rawConfig = `{
+		"raw_steps": [
+			{"as": "src", "input_image_tag_step": {"to": "src"}},
+			{"as": "bin", "template_step": {"name": "bin"}, "after": ["src"]},
+			{"as": "rpms", "rpm_serve_step": {}, "after": ["bin"]}
+		]
+	}`
+	var buildConfig api.ReleaseBuildConfiguration
+	if err := json.Unmarshal([]byte(rawConfig), &buildConfig); err != nil {
+		t.Fatalf("could not unmarshal build config: %v", err)
+	}
+
+	jobSpec := &JobSpec{Job: "test-job", BuildID: "1"}
+	clusterConfigs := map[string]*rest.Config{api.ClusterDefault: {}}
+
+	result, err := FromConfig(&buildConfig, jobSpec, clusterConfigs)
+	if err != nil {
+		t.Fatalf("FromConfig returned an error: %v", err)
+	}
+	if len(result) != 3 {
+		t.Fatalf("expected 3 steps, got %d", len(result))
+	}
+
+	requires := map[string][]string{}
+	for _, step := range result {
+		requires[step.Name()] = step.Requires()
+	}
+	if got, want := requires["src"], []string(nil); !reflect.DeepEqual(got, want) {
+		t.Errorf("src.Requires() = %v, want %v", got, want)
+	}
+	if got, want := requires["bin"], []string{"src"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("bin.Requires() = %v, want %v", got, want)
+	}
+	if got, want := requires["rpms"], []string{"bin"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("rpms.Requires() = %v, want %v", got, want)
+	}
+
+	roots := api.BuildGraph(result)
+	if len(roots) != 1 || roots[0].Step.Name() != "src" {
+		t.Fatalf("expected a single root step %q, got %#v", "src", roots)
+	}
+	if len(roots[0].Children) != 1 || roots[0].Children[0].Step.Name() != "bin" {
+		t.Fatalf("expected src's only child to be bin, got %#v", roots[0].Children)
+	}
+	binNode := roots[0].Children[0]
+	if len(binNode.Children) != 1 || binNode.Children[0].Step.Name() != "rpms" {
+		t.Fatalf("expected bin's only child to be rpms, got %#v", binNode.Children)
+	}
+}
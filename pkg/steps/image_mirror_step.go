@@ -0,0 +1,57 @@
+package steps
+
+import (
+	"fmt"
+	"log"
+
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/rest"
+
+	imageclientset "github.com/openshift/client-go/image/clientset/versioned/typed/image/v1"
+)
+
+// imageMirrorStep is the step for a single tag of the pipeline ImageStream, on whichever cluster
+// it was configured to run against. Run checks that the tag exists in the pipeline ImageStream and
+// fails if it does not; it does not copy the tag from another ImageStream.
+type imageMirrorStep struct {
+	name     string
+	tag      string
+	cluster  string
+	requires []string
+	jobSpec  *JobSpec
+	config   *rest.Config
+}
+
+func (s *imageMirrorStep) Name() string       { return s.name }
+func (s *imageMirrorStep) Requires() []string { return s.requires }
+func (s *imageMirrorStep) Cluster() string    { return s.cluster }
+
+func (s *imageMirrorStep) Run(dry bool) error {
+	if dry {
+		log.Printf("Would mirror tag %s into %s", s.tag, s.jobSpec.Namespace())
+		return nil
+	}
+	client, err := imageclientset.NewForConfig(s.config)
+	if err != nil {
+		return fmt.Errorf("could not get image client for cluster config: %v", err)
+	}
+	_, err = client.ImageStreamTags(s.jobSpec.Namespace()).Get(fmt.Sprintf("%s:%s", PipelineImageStream, s.tag), meta.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("could not mirror tag %s: %v", s.tag, err)
+	}
+	return nil
+}
+
+// Teardown deletes this step's tag from the pipeline ImageStream, so a namespace-pool reset or finished run doesn't
+// leave stray ImageStreamTags behind for the next job to trip over.
+func (s *imageMirrorStep) Teardown() error {
+	client, err := imageclientset.NewForConfig(s.config)
+	if err != nil {
+		return fmt.Errorf("could not get image client for cluster config: %v", err)
+	}
+	log.Printf("Unmirroring tag %s from %s", s.tag, s.jobSpec.Namespace())
+	if err := client.ImageStreamTags(s.jobSpec.Namespace()).Delete(fmt.Sprintf("%s:%s", PipelineImageStream, s.tag), nil); err != nil {
+		return fmt.Errorf("could not unmirror tag %s: %v", s.tag, err)
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+package steps
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// PipelineImageStream is the name of the ImageStream that intermediate pipeline images are
+	// pushed to for the lifetime of a single run.
+	PipelineImageStream = "pipeline"
+	// StableImageStream is the name of the ImageStream that tagged release images are read from.
+	StableImageStream = "stable"
+	// RPMRepoName is the name of the Route exposing the RPM repository built by the RPM-serve step.
+	RPMRepoName = "rpm-repo"
+)
+
+// JobSpec carries the identity of the Prow job that is driving this ci-operator invocation, as
+// read from the $JOB_SPEC environment variable.
+type JobSpec struct {
+	Job     string `json:"job"`
+	BuildID string `json:"buildid"`
+
+	namespace     string
+	baseNamespace string
+	owner         *meta.OwnerReference
+}
+
+// ResolveSpecFromEnv loads the JobSpec from the $JOB_SPEC environment variable that Prow sets for
+// every job it runs.
+func ResolveSpecFromEnv() (*JobSpec, error) {
+	raw, ok := os.LookupEnv("JOB_SPEC")
+	if !ok || raw == "" {
+		return nil, fmt.Errorf("$JOB_SPEC is not set or empty")
+	}
+	spec := &JobSpec{}
+	if err := json.Unmarshal([]byte(raw), spec); err != nil {
+		return nil, fmt.Errorf("malformed $JOB_SPEC: %v", err)
+	}
+	return spec, nil
+}
+
+// Hash returns a short, stable identifier for this job run, suitable for use in a namespace name.
+func (s *JobSpec) Hash() string {
+	sum := sha256.Sum256([]byte(s.Job + s.BuildID))
+	return hex.EncodeToString(sum[:])[:8]
+}
+
+func (s *JobSpec) SetNamespace(namespace string)       { s.namespace = namespace }
+func (s *JobSpec) Namespace() string                   { return s.namespace }
+func (s *JobSpec) SetBaseNamespace(namespace string)   { s.baseNamespace = namespace }
+func (s *JobSpec) BaseNamespace() string               { return s.baseNamespace }
+func (s *JobSpec) SetOwner(owner *meta.OwnerReference) { s.owner = owner }
+func (s *JobSpec) Owner() *meta.OwnerReference         { return s.owner }
@@ -0,0 +1,26 @@
+package steps
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	stepDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "ci_operator_step_duration_seconds",
+		Help:    "Duration of individual ci-operator step executions.",
+		Buckets: prometheus.ExponentialBuckets(1, 2, 12),
+	}, []string{"step"})
+
+	stepResults = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "ci_operator_step_result_total",
+		Help: "Count of ci-operator step executions by outcome.",
+	}, []string{"step", "phase"})
+)
+
+// recordMetrics reports a finished step's duration and outcome to the ci_operator_step_* metrics
+// exposed by --metrics-listen.
+func recordMetrics(result StepResult) {
+	stepDuration.WithLabelValues(result.Name).Observe(result.Duration().Seconds())
+	stepResults.WithLabelValues(result.Name, string(result.Phase)).Inc()
+}
@@ -0,0 +1,132 @@
+package steps
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"os"
+	"time"
+)
+
+// Phase is the outcome of a single step's execution.
+type Phase string
+
+const (
+	PhaseSucceeded Phase = "Succeeded"
+	PhaseFailed    Phase = "Failed"
+	PhaseSkipped   Phase = "Skipped"
+)
+
+// StepResult records what happened when a single step ran, for reporting to JUnit/JSON sinks and
+// Prometheus metrics.
+type StepResult struct {
+	Name      string    `json:"name"`
+	Cluster   string    `json:"cluster,omitempty"`
+	StartTime time.Time `json:"startTime"`
+	EndTime   time.Time `json:"endTime"`
+	Phase     Phase     `json:"phase"`
+	Error     string    `json:"error,omitempty"`
+}
+
+func (r StepResult) Duration() time.Duration {
+	return r.EndTime.Sub(r.StartTime)
+}
+
+// ResultSink consumes step results as they become available.
+type ResultSink interface {
+	Report(result StepResult) error
+}
+
+// MultiSink fans a result out to every sink in the slice, so e.g. JUnit and JSON reporting can run
+// side by side.
+type MultiSink []ResultSink
+
+func (m MultiSink) Report(result StepResult) error {
+	for _, sink := range m {
+		if err := sink.Report(result); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// junitSink accumulates results in memory and rewrites them as a single JUnit XML report after
+// every result it receives, for Prow/Spyglass ingestion.
+type junitSink struct {
+	path    string
+	results []StepResult
+}
+
+// NewJUnitSink returns a ResultSink that rewrites a JUnit XML report of all results so far to path
+// on every Report.
+func NewJUnitSink(path string) ResultSink {
+	return &junitSink{path: path}
+}
+
+type junitTestCase struct {
+	Name    string    `xml:"name,attr"`
+	Time    float64   `xml:"time,attr"`
+	Failure *string   `xml:"failure,omitempty"`
+	Skipped *struct{} `xml:"skipped,omitempty"`
+}
+
+type junitTestSuite struct {
+	XMLName   xml.Name        `xml:"testsuite"`
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	TestCases []junitTestCase `xml:"testcase"`
+}
+
+func (s *junitSink) Report(result StepResult) error {
+	s.results = append(s.results, result)
+	return s.flush()
+}
+
+func (s *junitSink) flush() error {
+	suite := junitTestSuite{Name: "ci-operator", Tests: len(s.results)}
+	for _, result := range s.results {
+		testCase := junitTestCase{Name: result.Name, Time: result.Duration().Seconds()}
+		switch result.Phase {
+		case PhaseFailed:
+			failure := result.Error
+			testCase.Failure = &failure
+			suite.Failures++
+		case PhaseSkipped:
+			testCase.Skipped = &struct{}{}
+		}
+		suite.TestCases = append(suite.TestCases, testCase)
+	}
+
+	encoded, err := xml.MarshalIndent(suite, "", "  ")
+	if err != nil {
+		return fmt.Errorf("could not marshal JUnit report: %v", err)
+	}
+	return os.WriteFile(s.path, encoded, 0644)
+}
+
+// jsonSink appends one JSON-encoded StepResult per line to path as results come in.
+type jsonSink struct {
+	path string
+}
+
+// NewJSONSink returns a ResultSink that appends a JSON-lines report of step results to path.
+func NewJSONSink(path string) ResultSink {
+	return &jsonSink{path: path}
+}
+
+func (s *jsonSink) Report(result StepResult) error {
+	encoded, err := json.Marshal(result)
+	if err != nil {
+		return fmt.Errorf("could not marshal step result: %v", err)
+	}
+	file, err := os.OpenFile(s.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("could not open results file: %v", err)
+	}
+	defer file.Close()
+	if _, err := file.Write(append(encoded, '\n')); err != nil {
+		return fmt.Errorf("could not write step result: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,47 @@
+package steps
+
+import (
+	"fmt"
+	"log"
+
+	"k8s.io/client-go/rest"
+
+	routeclientset "github.com/openshift/client-go/route/clientset/versioned/typed/route/v1"
+)
+
+// rpmServeStep is the step for serving the RPMs built by the pipeline over the RPMRepoName Route,
+// which the RPM_REPO parameter written out by the ci-operator invocation resolves to. Run only
+// logs in dry-run mode; otherwise it returns without creating the Route.
+type rpmServeStep struct {
+	name     string
+	cluster  string
+	requires []string
+	jobSpec  *JobSpec
+	config   *rest.Config
+}
+
+func (s *rpmServeStep) Name() string       { return s.name }
+func (s *rpmServeStep) Requires() []string { return s.requires }
+func (s *rpmServeStep) Cluster() string    { return s.cluster }
+
+func (s *rpmServeStep) Run(dry bool) error {
+	if dry {
+		log.Printf("Would expose RPM repo route %s", RPMRepoName)
+		return nil
+	}
+	return nil
+}
+
+// Teardown deletes the RPMRepoName Route, rather than leaving it for namespace deletion
+// (or, in namespace-pool mode, for the next run to stumble over).
+func (s *rpmServeStep) Teardown() error {
+	client, err := routeclientset.NewForConfig(s.config)
+	if err != nil {
+		return fmt.Errorf("could not get route client for cluster config: %v", err)
+	}
+	log.Printf("Releasing RPM repo route %s", RPMRepoName)
+	if err := client.Routes(s.jobSpec.Namespace()).Delete(RPMRepoName, nil); err != nil {
+		return fmt.Errorf("could not release RPM repo route: %v", err)
+	}
+	return nil
+}
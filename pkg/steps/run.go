@@ -0,0 +1,90 @@
+package steps
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/openshift/ci-operator/pkg/api"
+)
+
+// flatten collects every node reachable from the given roots exactly once, keyed by step name.
+func flatten(graph []*api.StepNode) map[string]*api.StepNode {
+	nodes := map[string]*api.StepNode{}
+	var visit func(node *api.StepNode)
+	visit = func(node *api.StepNode) {
+		if _, ok := nodes[node.Step.Name()]; ok {
+			return
+		}
+		nodes[node.Step.Name()] = node
+		for _, child := range node.Children {
+			visit(child)
+		}
+	}
+	for _, root := range graph {
+		visit(root)
+	}
+	return nodes
+}
+
+// Run executes every step in the graph in dependency order (Kahn's algorithm over each step's
+// Requires()), reporting a StepResult for each one to sink as it finishes, and returns every
+// result once the whole graph has run or the first unrecoverable step failure is hit.
+func Run(graph []*api.StepNode, dry bool, sink ResultSink) ([]StepResult, error) {
+	nodes := flatten(graph)
+
+	indegree := make(map[string]int, len(nodes))
+	for name, node := range nodes {
+		count := 0
+		for _, required := range node.Step.Requires() {
+			if _, ok := nodes[required]; ok {
+				count++
+			}
+		}
+		indegree[name] = count
+	}
+
+	var ready []string
+	for name, count := range indegree {
+		if count == 0 {
+			ready = append(ready, name)
+		}
+	}
+
+	var results []StepResult
+	for len(ready) > 0 {
+		name := ready[0]
+		ready = ready[1:]
+		node := nodes[name]
+
+		result := StepResult{Name: name, Cluster: node.Step.Cluster(), StartTime: time.Now()}
+		log.Printf("Running step %s", name)
+		err := node.Step.Run(dry)
+		result.EndTime = time.Now()
+		if err != nil {
+			result.Phase = PhaseFailed
+			result.Error = err.Error()
+		} else {
+			result.Phase = PhaseSucceeded
+		}
+		results = append(results, result)
+		recordMetrics(result)
+		if sink != nil {
+			if reportErr := sink.Report(result); reportErr != nil {
+				log.Printf("error: Failed to report result for step %s: %v", name, reportErr)
+			}
+		}
+		if err != nil {
+			return results, fmt.Errorf("step %s failed: %v", name, err)
+		}
+
+		for _, child := range node.Children {
+			indegree[child.Step.Name()]--
+			if indegree[child.Step.Name()] == 0 {
+				ready = append(ready, child.Step.Name())
+			}
+		}
+	}
+
+	return results, nil
+}
@@ -0,0 +1,83 @@
+package steps
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/openshift/ci-operator/pkg/api"
+)
+
+// orderingStep records its own name to a shared slice when it runs, so tests can assert on the
+// order Run executed the graph in without depending on wall-clock timestamps.
+type orderingStep struct {
+	name     string
+	requires []string
+	order    *[]string
+	fail     bool
+}
+
+func (s *orderingStep) Name() string       { return s.name }
+func (s *orderingStep) Requires() []string { return s.requires }
+func (s *orderingStep) Cluster() string    { return "" }
+func (s *orderingStep) Run(dry bool) error {
+	*s.order = append(*s.order, s.name)
+	if s.fail {
+		return fmt.Errorf("%s failed", s.name)
+	}
+	return nil
+}
+
+func indexOf(order []string, name string) int {
+	for i, n := range order {
+		if n == name {
+			return i
+		}
+	}
+	return -1
+}
+
+func TestRunOrdersByDependency(t *testing.T) {
+	var order []string
+	steps := []api.Step{
+		&orderingStep{name: "src", order: &order},
+		&orderingStep{name: "build-a", requires: []string{"src"}, order: &order},
+		&orderingStep{name: "build-b", requires: []string{"src"}, order: &order},
+		&orderingStep{name: "test", requires: []string{"build-a", "build-b"}, order: &order},
+	}
+	graph := api.BuildGraph(steps)
+
+	results, err := Run(graph, true, nil)
+	if err != nil {
+		t.Fatalf("Run returned an error: %v", err)
+	}
+	if len(results) != len(steps) {
+		t.Fatalf("expected %d results, got %d", len(steps), len(results))
+	}
+
+	if indexOf(order, "src") > indexOf(order, "build-a") || indexOf(order, "src") > indexOf(order, "build-b") {
+		t.Fatalf("src must run before both build steps, got order %v", order)
+	}
+	if indexOf(order, "build-a") > indexOf(order, "test") || indexOf(order, "build-b") > indexOf(order, "test") {
+		t.Fatalf("both build steps must run before test, got order %v", order)
+	}
+}
+
+func TestRunStopsOnFailure(t *testing.T) {
+	var order []string
+	steps := []api.Step{
+		&orderingStep{name: "src", order: &order, fail: true},
+		&orderingStep{name: "build", requires: []string{"src"}, order: &order},
+	}
+	graph := api.BuildGraph(steps)
+
+	results, err := Run(graph, true, nil)
+	if err == nil {
+		t.Fatal("expected Run to return an error when a step fails")
+	}
+	if len(results) != 1 || results[0].Phase != PhaseFailed {
+		t.Fatalf("expected a single failed result, got %#v", results)
+	}
+	if len(order) != 1 {
+		t.Fatalf("expected the dependent step to be skipped, but it ran: %v", order)
+	}
+}
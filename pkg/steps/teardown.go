@@ -0,0 +1,59 @@
+package steps
+
+import (
+	"fmt"
+	"log"
+
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+
+	"github.com/openshift/ci-operator/pkg/api"
+)
+
+// Teardown runs the cleanup half of the build graph: image-mirror, RPM-serve, template and any
+// other TeardownStep implementations get to delete tags, delete routes or record an artifacts
+// ConfigMap instead of relying solely on namespace deletion.
+type Teardown struct {
+	graph []*api.StepNode
+}
+
+// TeardownGraph prepares the teardown pipeline for a build graph, to be run on SIGTERM/SIGINT or
+// in --teardown mode.
+func TeardownGraph(graph []*api.StepNode) *Teardown {
+	return &Teardown{graph: graph}
+}
+
+// Run tears down every step that implements TeardownStep, each one only after every step that
+// depends on it (children before parents), so e.g. a template step is torn down before the
+// image-mirror step it depended on deletes the tags that template might still be reading. Steps
+// with no dependency between them are torn down in graph order.
+func (t *Teardown) Run() error {
+	visited := map[string]bool{}
+	var errs []error
+
+	var visit func(node *api.StepNode)
+	visit = func(node *api.StepNode) {
+		name := node.Step.Name()
+		if visited[name] {
+			return
+		}
+		visited[name] = true
+
+		for _, child := range node.Children {
+			visit(child)
+		}
+
+		teardown, ok := node.Step.(api.TeardownStep)
+		if !ok {
+			return
+		}
+		log.Printf("Tearing down step %s", name)
+		if err := teardown.Teardown(); err != nil {
+			errs = append(errs, fmt.Errorf("step %s: %v", name, err))
+		}
+	}
+	for _, root := range t.graph {
+		visit(root)
+	}
+
+	return utilerrors.NewAggregate(errs)
+}
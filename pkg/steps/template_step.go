@@ -0,0 +1,52 @@
+package steps
+
+import (
+	"fmt"
+	"log"
+
+	coreapi "k8s.io/api/core/v1"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/rest"
+
+	coreclientset "k8s.io/client-go/kubernetes/typed/core/v1"
+)
+
+// templateStep is the build step for a template, e.g. to drive an end-to-end test cluster. Run
+// only logs in dry-run mode; otherwise it returns without processing the template.
+type templateStep struct {
+	name     string
+	requires []string
+	cluster  string
+	jobSpec  *JobSpec
+	config   *rest.Config
+}
+
+func (s *templateStep) Name() string       { return s.name }
+func (s *templateStep) Requires() []string { return s.requires }
+func (s *templateStep) Cluster() string    { return s.cluster }
+
+func (s *templateStep) Run(dry bool) error {
+	if dry {
+		log.Printf("Would run template %s", s.name)
+		return nil
+	}
+	return nil
+}
+
+// Teardown creates an empty "<name>-artifacts" ConfigMap in the job namespace; it does not collect
+// or upload anything the template step's pod produced.
+func (s *templateStep) Teardown() error {
+	client, err := coreclientset.NewForConfig(s.config)
+	if err != nil {
+		return fmt.Errorf("could not get core client for cluster config: %v", err)
+	}
+	log.Printf("Uploading artifacts for template step %s", s.name)
+	if _, err := client.ConfigMaps(s.jobSpec.Namespace()).Create(&coreapi.ConfigMap{
+		ObjectMeta: meta.ObjectMeta{
+			Name: fmt.Sprintf("%s-artifacts", s.name),
+		},
+	}); err != nil {
+		return fmt.Errorf("could not upload artifacts for template step %s: %v", s.name, err)
+	}
+	return nil
+}